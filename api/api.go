@@ -1,13 +1,24 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"io"
+	"math/rand"
+	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
 
 	"github.com/jcdotter/go/buffer"
 	"github.com/jcdotter/go/data"
 	"github.com/jcdotter/go/encoder"
+	"github.com/jcdotter/go/errors"
 )
 
 // ----------------------------------------------------------------------------
@@ -159,6 +170,13 @@ type Api struct {
 	Protocol  Protocol
 	Auth      *Api
 	Resources *data.Data
+	// Client is the http client used to make requests.
+	// if nil, http.DefaultClient is used.
+	Client *http.Client
+	// MaxRetries is the number of times a method call
+	// will be retried when it fails with a retryable
+	// errors.Code. defaults to 0 (no retries).
+	MaxRetries int
 }
 
 func New() *Api {
@@ -168,6 +186,15 @@ func New() *Api {
 	}
 }
 
+// httpClient returns the api's configured client,
+// or http.DefaultClient if none was set.
+func (a *Api) httpClient() *http.Client {
+	if a.Client != nil {
+		return a.Client
+	}
+	return http.DefaultClient
+}
+
 func FromYaml(yaml []byte) *Api {
 	return FromMap(encoder.Yaml.Decode(yaml).Map())
 }
@@ -194,6 +221,9 @@ func (a *Api) ResourceMap(k string, m map[string]any, u *url.URL) {
 		u.Path = uri.(string)
 		r := NewResource(a, k, u)
 		a.Resources.Add(r)
+		if ct, ok := m["contentType"]; ok {
+			r.ContentType = Contenttype(ct.(string))
+		}
 		if ms, ok := m["methods"]; ok {
 			for k, v := range ms.(map[string]any) {
 				r.MethodMap(k, v.(map[string]any))
@@ -218,14 +248,22 @@ type Resource struct {
 	Name    string
 	Url     *url.URL
 	Methods *data.Data
+	// ContentType is the content type used to marshal
+	// and unmarshal the body of requests and responses
+	// made to this resource. defaults to JSON.
+	ContentType ContentType
+	// grpcConn is the dialed gRPC connection used when
+	// Api.Protocol is GRPC. see grpc.go.
+	grpcConn *grpc.ClientConn
 }
 
 func NewResource(api *Api, name string, url *url.URL) *Resource {
 	return &Resource{
-		Api:     api,
-		Name:    name,
-		Url:     url,
-		Methods: data.Make[*Method](4),
+		Api:         api,
+		Name:        name,
+		Url:         url,
+		Methods:     data.Make[*Method](4),
+		ContentType: JSON,
 	}
 }
 
@@ -263,13 +301,23 @@ func (r *Resource) Delete() {}
 type Method struct {
 	Resource *Resource
 	Name     string
+	Type     MethodType
 	Request  *Request
 	Response *Response
+
+	// deadline state, see deadline.go
+	mu          sync.Mutex
+	writeTimer  *time.Timer
+	readTimer   *time.Timer
+	writeCancel chan struct{}
+	readCancel  chan struct{}
 }
 
 func NewMethod(resource *Resource, name string) *Method {
 	return &Method{
+		Resource: resource,
 		Name:     name,
+		Type:     Methodtype(strings.ToUpper(name)),
 		Request:  &Request{},
 		Response: &Response{},
 	}
@@ -279,11 +327,89 @@ func (m *Method) Key() string {
 	return m.Name
 }
 
-func (m *Method) Call() {
-	// use http client to build and make request
-	/* c := &http.Client{}
-	r, _ := http.NewRequest(m.Name, m.Resource.Url.String(), nil) */
+// retryable reports whether an error of the given code
+// is safe to retry, i.e. the failure is transient and the
+// request is expected to be idempotent.
+func retryable(c errors.Code) bool {
+	switch c {
+	case errors.UNAVAILABLE, errors.DEADLINE, errors.ABORTED, errors.EXHAUSTED:
+		return true
+	}
+	return false
+}
+
+// backoff returns the delay to wait before the given retry
+// attempt (0-indexed), using exponential backoff with jitter.
+func backoff(attempt int) time.Duration {
+	base := 100 * time.Millisecond
+	max := base << attempt
+	return time.Duration(rand.Int63n(int64(max))) + base
+}
+
+// Call builds and executes the request against the resource's url,
+// retrying on transient failures up to the api's MaxRetries, and
+// decodes the response body on success. On a non-2xx response, the
+// http status is mapped to an errors.Code and returned as an
+// *errors.Status. Call is equivalent to CallContext(context.Background()).
+func (m *Method) Call() error {
+	return m.CallContext(context.Background())
+}
+
+// httpRequest builds the *http.Request for the method's call,
+// applying the request's params as a query string, the request's
+// header params as http headers, and the request's body marshalled
+// per the resource's ContentType.
+func (m *Method) httpRequest() (*http.Request, error) {
+	u := *m.Resource.Url
+	if !m.Request.Params.IsNil() {
+		q := u.Query()
+		for i := 0; i < m.Request.Params.Len(); i++ {
+			p := m.Request.Params.Index(i)
+			q.Set(p.Key(), fmt.Sprint(p.Val()))
+		}
+		u.RawQuery = q.Encode()
+	}
+	req, err := http.NewRequest(strings.TrimSpace(m.Type.String()), u.String(), m.Request.Reader(m.Resource.ContentType))
+	if err != nil {
+		return nil, errors.Invalid(err.Error())
+	}
+	if !m.Request.Header.IsNil() {
+		for i := 0; i < m.Request.Header.Len(); i++ {
+			h := m.Request.Header.Index(i)
+			req.Header.Set(h.Key(), fmt.Sprint(h.Val()))
+		}
+	}
+	if req.Header.Get("Content-Type") == "" && req.Body != nil {
+		req.Header.Set("Content-Type", m.Resource.ContentType.String())
+	}
+	return req, nil
+}
 
+// handleResponse decodes a successful response into the method's
+// Response.Body, respecting the resource's ContentType, or returns an
+// *errors.Status derived from the response's http status code.
+func (m *Method) handleResponse(resp *http.Response) error {
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.NewStatus(errors.FromHttp(resp.StatusCode), resp.Status)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Internal(err.Error())
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	switch m.Resource.ContentType {
+	case JSON:
+		_, m.Response.Body = ParamMap(encoder.Json.Decode(b).Map())
+	default:
+		// TODO: decode XML/FORM/other content types once their
+		// encoders are available; store the raw body for now.
+		m.Response.Body = Params{data.Make[*Param](1)}
+		m.Response.Body.Add(ParamElem("body", string(b)))
+	}
+	return nil
 }
 
 type Request struct {
@@ -321,8 +447,27 @@ func RequestMap(m map[string]any) *Request {
 	return r
 }
 
-func (r *Request) Reader() io.Reader {
-	//b :=
+// Reader marshals the request body and returns it as an io.Reader
+// suitable for an http.Request, or nil if the request has no body.
+// A FORM body is url-encoded; every other content type is
+// marshalled as JSON.
+func (r *Request) Reader(ct ContentType) io.Reader {
+	if r.Body.IsNil() || r.Body.Len() == 0 {
+		return nil
+	}
+	switch ct {
+	case FORM:
+		v := url.Values{}
+		for i := 0; i < r.Body.Len(); i++ {
+			p := r.Body.Index(i)
+			v.Set(p.Key(), fmt.Sprint(p.Val()))
+		}
+		return strings.NewReader(v.Encode())
+	default:
+		if b := r.Body.Json(); b != nil {
+			return bytes.NewReader(b)
+		}
+	}
 	return nil
 }
 
@@ -576,7 +721,7 @@ func (p *Params) Json(list ...bool) []byte {
 		}
 		v := p.Index(i)
 		b.WriteString(strconv.Quote(v.Key()))
-		b.WriteByte(',')
+		b.WriteByte(':')
 		b.Write(v.Json())
 	}
 	b.WriteByte('}')
@@ -596,7 +741,7 @@ func (p *Param) Json() []byte {
 			case BOOL:
 				return []byte(strconv.FormatBool(p.val.(bool)))
 			case INT:
-				return []byte(strconv.FormatInt(p.val.(int64), 10))
+				return []byte(strconv.FormatInt(int64(p.val.(int)), 10))
 			case FLOAT:
 				return []byte(strconv.FormatFloat(p.val.(float64), 'f', -1, 64))
 			case STRING:
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/jcdotter/go/errors"
+)
+
+// ----------------------------------------------------------------------------
+// DEADLINES
+// Method carries two independent deadlines, following the netstack
+// gonet adapter's split between read and write lifetimes: one for
+// the request-write phase (marshalling + sending the body, up to the
+// arrival of the response headers) and one for the response-read
+// phase (streaming body decode). Each is guarded by an AfterFunc
+// timer, reset whenever the deadline is changed mid-flight, so
+// long-poll and websocket-upgrade resources can size each phase
+// independently.
+
+// SetDeadline sets both the write and read deadlines for the
+// method's next call(s). A zero Time disables both deadlines.
+func (m *Method) SetDeadline(t time.Time) {
+	m.SetWriteDeadline(t)
+	m.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for the request-write phase
+// (marshalling and sending the request). A zero Time disables the
+// deadline. Safe to call mid-flight: the previous timer is stopped
+// and a new one armed in its place.
+func (m *Method) SetWriteDeadline(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// keep the channel an in-flight call is watching if its timer
+	// had not fired yet, so the new deadline reaches that call.
+	c := m.writeCancel
+	if m.writeTimer == nil || !m.writeTimer.Stop() {
+		c = nil
+	}
+	m.writeCancel = nil
+	if !t.IsZero() {
+		if c == nil {
+			c = make(chan struct{})
+		}
+		m.writeCancel = c
+		m.writeTimer = time.AfterFunc(time.Until(t), func() { close(c) })
+	}
+}
+
+// SetReadDeadline sets the deadline for the response-read phase
+// (streaming body decode, after the headers have arrived). A zero
+// Time disables the deadline. Safe to call mid-flight: the previous
+// timer is stopped and a new one armed in its place.
+func (m *Method) SetReadDeadline(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// keep the channel an in-flight call is watching if its timer
+	// had not fired yet, so the new deadline reaches that call.
+	c := m.readCancel
+	if m.readTimer == nil || !m.readTimer.Stop() {
+		c = nil
+	}
+	m.readCancel = nil
+	if !t.IsZero() {
+		if c == nil {
+			c = make(chan struct{})
+		}
+		m.readCancel = c
+		m.readTimer = time.AfterFunc(time.Until(t), func() { close(c) })
+	}
+}
+
+func (m *Method) writeCancelChan() chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.writeCancel
+}
+
+func (m *Method) readCancelChan() chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.readCancel
+}
+
+// watchCancel cancels the returned context's parent cancel func when
+// c fires, and returns a stop func that must be called once the
+// guarded phase completes so the goroutine can exit. c may be nil,
+// in which case watchCancel is a no-op.
+func watchCancel(c chan struct{}, cancel context.CancelFunc) (stop func()) {
+	if c == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-c:
+			cancel()
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
+
+// CallContext performs the method call honoring ctx in addition to
+// any write/read deadlines set via SetDeadline/SetWriteDeadline/
+// SetReadDeadline. The write phase (marshalling and sending the
+// request, up to the arrival of the response headers) is bounded by
+// the write deadline and reports errors.Deadline on expiry; the read
+// phase (response body) is bounded by the read deadline and reports
+// errors.Cancelled on expiry, matching an aborted reader. When the
+// api's Protocol is GRPC, each attempt is bounded by ctx alone; the
+// write and read deadlines are not applied.
+func (m *Method) CallContext(ctx context.Context) error {
+	a := m.Resource.Api
+	var err error
+	for attempt := 0; ; attempt++ {
+		if a.Protocol == GRPC {
+			err = m.grpcCall(ctx)
+		} else {
+			err = m.roundTrip(ctx, a.httpClient())
+		}
+		if err == nil {
+			return nil
+		}
+		status, ok := err.(*errors.Status)
+		if !ok || !retryable(status.Code()) || attempt >= a.MaxRetries {
+			return err
+		}
+		time.Sleep(backoff(attempt))
+	}
+}
+
+// roundTrip performs a single request/response cycle, cancelling
+// the write phase (up to the response headers) via the write
+// deadline/ctx and the read phase (the body) via the read
+// deadline/ctx.
+func (m *Method) roundTrip(ctx context.Context, client *http.Client) error {
+	writeCtx, cancelWrite := context.WithCancel(ctx)
+	defer cancelWrite()
+	stopWrite := watchCancel(m.writeCancelChan(), cancelWrite)
+
+	req, err := m.httpRequest()
+	if err != nil {
+		stopWrite()
+		return err
+	}
+	req = req.WithContext(writeCtx)
+
+	resp, err := client.Do(req)
+	stopWrite()
+	if err != nil {
+		if writeCtx.Err() != nil && ctx.Err() == nil {
+			return errors.Deadline(m.Name + ": request write deadline exceeded")
+		}
+		return errors.Unavailable(err.Error())
+	}
+
+	readCtx, cancelRead := context.WithCancel(ctx)
+	defer cancelRead()
+	stopRead := watchCancel(m.readCancelChan(), cancelRead)
+	defer stopRead()
+
+	done := make(chan error, 1)
+	go func() { done <- m.handleResponse(resp) }()
+	select {
+	case err := <-done:
+		return err
+	case <-readCtx.Done():
+		resp.Body.Close()
+		return errors.Cancelled(m.Name + ": response read aborted")
+	}
+}
@@ -0,0 +1,306 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/jcdotter/go/errors"
+)
+
+// ----------------------------------------------------------------------------
+// GRPC TRANSPORT
+// builds request/response messages from the Param tree via proto
+// reflection, so that a Resource/Method assembled from FromYaml/FromMap
+// (or FromOpenAPI) can be invoked against a gRPC server with no
+// generated stubs required at call time.
+
+// conn returns a dialed connection to the resource's gRPC server,
+// dialing a new one if the api has not already dialed one.
+func (r *Resource) conn() (*grpc.ClientConn, error) {
+	if r.grpcConn != nil {
+		return r.grpcConn, nil
+	}
+	conn, err := grpc.Dial(r.Url.Host, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, errors.Unavailable(err.Error())
+	}
+	r.grpcConn = conn
+	return conn, nil
+}
+
+// grpcFullMethod derives the fully qualified gRPC method name from
+// the resource and method names, e.g. "/UserService/Get".
+func (m *Method) grpcFullMethod() string {
+	return "/" + strings.Title(m.Resource.Name) + "Service/" + strings.Title(m.Name)
+}
+
+// grpcCall invokes the method as a unary gRPC call, marshalling
+// Request.Body into a dynamic proto message built from the Param
+// tree and unmarshalling the response into Response.Body.
+func (m *Method) grpcCall(ctx context.Context) error {
+	conn, err := m.Resource.conn()
+	if err != nil {
+		return err
+	}
+	in := paramsToMessage(m.Name+"Request", m.Request.Body)
+	out := dynamicpb.NewMessage(messageDescriptor(m.Name+"Response", m.Response.Body))
+	if err = conn.Invoke(ctx, m.grpcFullMethod(), in, out); err != nil {
+		return errors.NewStatus(errors.FromGrpc(grpcCode(err)), err.Error())
+	}
+	m.Response.Body = messageToParams(out)
+	return nil
+}
+
+// paramsToMessage builds a dynamic proto message populated with the
+// values held in p, using a message descriptor derived from the
+// Param tree's DataType graph.
+func paramsToMessage(name string, p Params) *dynamicpb.Message {
+	return paramsToMessageDescriptor(messageDescriptor(name, p), p)
+}
+
+// paramsToMessageDescriptor builds a dynamic message for p against an
+// already-resolved message descriptor. It is the shared
+// implementation behind paramsToMessage and setField's OBJECT/
+// LIST-of-OBJECT recursion, where the nested descriptor comes from
+// the parent field rather than a fresh call to messageDescriptor.
+func paramsToMessageDescriptor(md protoreflect.MessageDescriptor, p Params) *dynamicpb.Message {
+	msg := dynamicpb.NewMessage(md)
+	if p.IsNil() {
+		return msg
+	}
+	for i := 0; i < p.Len(); i++ {
+		el := p.Index(i)
+		fd := md.Fields().ByName(protoreflect.Name(fieldName(el.Key())))
+		if fd == nil {
+			continue
+		}
+		setField(msg, fd, el)
+	}
+	return msg
+}
+
+// messageDescriptor builds a proto message descriptor from a Param
+// tree, mapping each Param's DataType to the corresponding proto
+// field type, and recursing into OBJECT/LIST-of-OBJECT elements to
+// build sibling nested message descriptors in the same file.
+func messageDescriptor(name string, p Params) protoreflect.MessageDescriptor {
+	msgs := []*descriptorpb.DescriptorProto{{Name: strPtr(name)}}
+	buildFields(&msgs, 0, p)
+	return newMessageType(msgs).Descriptor()
+}
+
+// buildFields appends the fields for p onto msgs[idx], appending any
+// sibling message descriptors needed by p's OBJECT/LIST-of-OBJECT
+// fields to msgs as it goes.
+func buildFields(msgs *[]*descriptorpb.DescriptorProto, idx int, p Params) {
+	if p.IsNil() {
+		return
+	}
+	for i := 0; i < p.Len(); i++ {
+		el := p.Index(i)
+		(*msgs)[idx].Field = append((*msgs)[idx].Field, fieldDescriptor(msgs, el, int32(i+1)))
+	}
+}
+
+// fieldDescriptor maps a single Param to a FieldDescriptorProto,
+// appending a sibling message descriptor to msgs for an OBJECT field,
+// or for a LIST field whose elements are OBJECT, so the field's
+// TypeName resolves within the same FileDescriptorProto.
+func fieldDescriptor(msgs *[]*descriptorpb.DescriptorProto, p *Param, num int32) *descriptorpb.FieldDescriptorProto {
+	f := &descriptorpb.FieldDescriptorProto{
+		Name:   strPtr(fieldName(p.Key())),
+		Number: int32Ptr(num),
+	}
+	switch p.Type() {
+	case BOOL:
+		f.Type = descriptorpb.FieldDescriptorProto_TYPE_BOOL.Enum()
+	case INT:
+		f.Type = descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum()
+	case FLOAT:
+		f.Type = descriptorpb.FieldDescriptorProto_TYPE_DOUBLE.Enum()
+	case OBJECT:
+		f.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+		f.TypeName = strPtr("." + addNestedMessage(msgs, p.Key(), p.Elems()))
+	case LIST:
+		f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		switch p.ElemType() {
+		case BOOL:
+			f.Type = descriptorpb.FieldDescriptorProto_TYPE_BOOL.Enum()
+		case INT:
+			f.Type = descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum()
+		case FLOAT:
+			f.Type = descriptorpb.FieldDescriptorProto_TYPE_DOUBLE.Enum()
+		case OBJECT:
+			f.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			var fields Params
+			if p.Len() > 0 {
+				fields = p.Index(0).Elems()
+			}
+			f.TypeName = strPtr("." + addNestedMessage(msgs, p.Key(), fields))
+		default:
+			f.Type = descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()
+		}
+	default: // STRING, ANY, NONE
+		f.Type = descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()
+	}
+	return f
+}
+
+// addNestedMessage appends a new, uniquely-named sibling message
+// descriptor built from fields to msgs and returns its name.
+func addNestedMessage(msgs *[]*descriptorpb.DescriptorProto, key string, fields Params) string {
+	idx := len(*msgs)
+	name := fmt.Sprintf("%sMsg%d", strings.Title(fieldName(key)), idx)
+	*msgs = append(*msgs, &descriptorpb.DescriptorProto{Name: strPtr(name)})
+	buildFields(msgs, idx, fields)
+	return name
+}
+
+// setField assigns a Param's value onto the dynamic message field it
+// was derived from. OBJECT values recurse into a nested message
+// built from the field's own descriptor; LIST values populate a
+// repeated field, recursing per element for a list of objects.
+func setField(msg *dynamicpb.Message, fd protoreflect.FieldDescriptor, p *Param) {
+	switch p.Type() {
+	case OBJECT:
+		msg.Set(fd, protoreflect.ValueOfMessage(paramsToMessageDescriptor(fd.Message(), p.Elems())))
+	case LIST:
+		list := msg.NewField(fd).List()
+		for i := 0; i < p.Len(); i++ {
+			el := p.Index(i)
+			if el.Type() == OBJECT {
+				list.Append(protoreflect.ValueOfMessage(paramsToMessageDescriptor(fd.Message(), el.Elems())))
+			} else if v := scalarValue(el); v.IsValid() {
+				list.Append(v)
+			}
+		}
+		msg.Set(fd, protoreflect.ValueOfList(list))
+	default:
+		if v := scalarValue(p); v.IsValid() {
+			msg.Set(fd, v)
+		}
+	}
+}
+
+// scalarValue converts a BOOL/INT/FLOAT/STRING Param's value to the
+// corresponding protoreflect.Value, or the zero Value for any other
+// (unset or composite) Param.
+func scalarValue(p *Param) protoreflect.Value {
+	switch v := p.Val().(type) {
+	case bool:
+		return protoreflect.ValueOfBool(v)
+	case int:
+		return protoreflect.ValueOfInt64(int64(v))
+	case float64:
+		return protoreflect.ValueOfFloat64(v)
+	case string:
+		return protoreflect.ValueOfString(v)
+	}
+	return protoreflect.Value{}
+}
+
+// messageToParams walks a dynamic proto message and rebuilds the
+// equivalent Param tree, the inverse of paramsToMessage.
+func messageToParams(msg *dynamicpb.Message) Params {
+	_, params := ParamMap(messageToMap(msg))
+	return params
+}
+
+// messageToMap converts a dynamic proto message to the map[string]any
+// shape ParamMap/ParamElem expect, recursing into nested messages and
+// repeated fields so every field type scalarValue/setField can write
+// round-trips back, not just the ones that happen to satisfy
+// v.Interface()'s native Go type.
+func messageToMap(msg *dynamicpb.Message) map[string]any {
+	m := make(map[string]any, msg.Descriptor().Fields().Len())
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		m[string(fd.Name())] = fieldValue(fd, v)
+		return true
+	})
+	return m
+}
+
+// fieldValue converts a single field's value to a ParamElem-compatible
+// Go value, expanding a repeated field to a []any of per-element
+// values.
+func fieldValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) any {
+	if fd.IsList() {
+		list := v.List()
+		out := make([]any, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			out[i] = elemValue(fd, list.Get(i))
+		}
+		return out
+	}
+	return elemValue(fd, v)
+}
+
+// elemValue converts a single scalar or message field value (or one
+// element of a repeated field) to a ParamElem-compatible Go value.
+func elemValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) any {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return v.Bool()
+	case protoreflect.Int32Kind, protoreflect.Int64Kind,
+		protoreflect.Sint32Kind, protoreflect.Sint64Kind,
+		protoreflect.Sfixed32Kind, protoreflect.Sfixed64Kind:
+		return int(v.Int())
+	case protoreflect.Uint32Kind, protoreflect.Uint64Kind,
+		protoreflect.Fixed32Kind, protoreflect.Fixed64Kind:
+		return int(v.Uint())
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return v.Float()
+	case protoreflect.StringKind:
+		return v.String()
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageToMap(v.Message().Interface().(*dynamicpb.Message))
+	default:
+		return nil
+	}
+}
+
+// fieldName normalizes a Param key into a valid proto field name.
+func fieldName(key string) string {
+	return strings.ToLower(key)
+}
+
+// newMessageType wraps one or more DescriptorProtos - the root
+// message first, followed by any sibling messages referenced by a
+// "." + Name TypeName (see addNestedMessage) - in a standalone
+// FileDescriptorProto and builds the runtime protoreflect.MessageType
+// for the root, so a message shape derived purely from the Param
+// tree can be used with dynamicpb.
+func newMessageType(dps []*descriptorpb.DescriptorProto) protoreflect.MessageType {
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:        strPtr(*dps[0].Name + ".proto"),
+		Syntax:      strPtr("proto3"),
+		MessageType: dps,
+	}
+	fd, err := protodesc.NewFile(fdp, nil)
+	if err != nil {
+		// the descriptor is assembled from the Param tree and
+		// should always be well formed; a failure here indicates
+		// a bug in fieldDescriptor, not bad caller input.
+		panic("api: invalid generated descriptor: " + err.Error())
+	}
+	return dynamicpb.NewMessageType(fd.Messages().Get(0))
+}
+
+// grpcCode extracts the gRPC status code carried by err, or
+// codes.Unknown if err did not originate from a gRPC call.
+func grpcCode(err error) codes.Code {
+	return grpcstatus.Code(err)
+}
+
+func strPtr(s string) *string { return &s }
+func int32Ptr(i int32) *int32 { return &i }
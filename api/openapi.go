@@ -0,0 +1,299 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/jcdotter/go/data"
+	"github.com/jcdotter/go/encoder"
+)
+
+// ----------------------------------------------------------------------------
+// OPENAPI 3
+// FromOpenAPI and ToOpenAPI let an Api be assembled from, or
+// published as, an OpenAPI 3 document, using the same Resource/
+// Method/Param tree that FromYaml/FromMap build, so a client
+// generated from an existing spec and one assembled programmatically
+// are indistinguishable to the rest of the package.
+
+// FromOpenAPI parses an OpenAPI 3 document and returns the
+// equivalent Api: each path becomes a Resource, each operation
+// (get/post/put/...) becomes a Method, parameters become
+// Request.Params/Header, and requestBody/responses' JSON schema
+// becomes Request.Body/Response.Body.
+func FromOpenAPI(spec []byte) (api *Api) {
+	m := encoder.Yaml.Decode(spec).Map()
+	paths, ok := m["paths"].(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	base := &url.URL{}
+	if servers, ok := m["servers"].([]any); ok && len(servers) > 0 {
+		if s, ok := servers[0].(map[string]any); ok {
+			if u, ok := s["url"].(string); ok {
+				if parsed, err := url.Parse(u); err == nil {
+					base = parsed
+				}
+			}
+		}
+	}
+
+	api = New()
+	for p, v := range paths {
+		item, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		u := *base
+		u.Path = p
+		name := strings.Trim(p, "/")
+		if name == "" {
+			name = "root"
+		}
+		r := NewResource(api, name, &u)
+		api.Resources.Add(r)
+		for verb, op := range item {
+			if operation, ok := op.(map[string]any); ok {
+				r.OperationMap(verb, operation)
+			}
+		}
+	}
+	return
+}
+
+// OperationMap adds a Method to the resource from a single OpenAPI
+// operation object (the value found at paths.{path}.{verb}).
+func (r *Resource) OperationMap(verb string, op map[string]any) {
+	name := verb
+	if id, ok := op["operationId"].(string); ok && id != "" {
+		name = id
+	}
+	me := NewMethod(r, name)
+	me.Type = Methodtype(strings.ToUpper(verb))
+
+	if params, ok := op["parameters"].([]any); ok && len(params) > 0 {
+		me.Request.Params = Params{data.Make[*Param](len(params))}
+		me.Request.Header = Params{data.Make[*Param](len(params))}
+		for _, pv := range params {
+			p, ok := pv.(map[string]any)
+			if !ok {
+				continue
+			}
+			pname, _ := p["name"].(string)
+			schema, _ := p["schema"].(map[string]any)
+			param := paramFromSchema(pname, schema)
+			if in, _ := p["in"].(string); in == "header" {
+				me.Request.Header.Add(param)
+			} else {
+				me.Request.Params.Add(param)
+			}
+		}
+	}
+	if schema := contentSchema(op, "requestBody"); schema != nil {
+		_, me.Request.Body = paramsFromSchema(schema)
+	}
+	if resps, ok := op["responses"].(map[string]any); ok {
+		for _, code := range []string{"200", "201", "default"} {
+			if resp, ok := resps[code].(map[string]any); ok {
+				if schema := contentSchema(resp, ""); schema != nil {
+					_, me.Response.Body = paramsFromSchema(schema)
+				}
+				break
+			}
+		}
+	}
+	r.Methods.Add(me)
+}
+
+// contentSchema extracts the application/json schema from an
+// OpenAPI requestBody or response object. If field is non-empty,
+// it is first looked up as a nested object on m (as for requestBody);
+// otherwise m itself is treated as the containing object (as for a
+// single response entry).
+func contentSchema(m map[string]any, field string) map[string]any {
+	obj := m
+	if field != "" {
+		o, ok := m[field].(map[string]any)
+		if !ok {
+			return nil
+		}
+		obj = o
+	}
+	content, ok := obj["content"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	media, ok := content["application/json"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	schema, _ := media["schema"].(map[string]any)
+	return schema
+}
+
+// paramFromSchema builds a *Param shaped by a JSON Schema fragment,
+// inferring the DataType from its "type" keyword ("format" is not
+// read) and recursing into "properties" (object) or "items" (array).
+func paramFromSchema(key string, schema map[string]any) *Param {
+	p := &Param{key: key}
+	t, _ := schema["type"].(string)
+	switch t {
+	case "boolean":
+		p.typ = BOOL
+	case "integer":
+		p.typ = INT
+	case "number":
+		p.typ = FLOAT
+	case "string":
+		p.typ = STRING
+	case "array":
+		p.typ = LIST
+		if items, ok := schema["items"].(map[string]any); ok {
+			_, p.els = paramsFromSchema(items)
+			p.elm = paramFromSchema("", items).typ
+		}
+	case "object":
+		p.typ = OBJECT
+		p.elm, p.els = paramsFromSchema(schema)
+	default:
+		p.typ = ANY
+	}
+	return p
+}
+
+// paramsFromSchema builds the Params for an object schema's
+// properties, mirroring ParamMap's element-type tracking.
+func paramsFromSchema(schema map[string]any) (e DataType, d Params) {
+	props, _ := schema["properties"].(map[string]any)
+	d = Params{data.Make[*Param](len(props))}
+	i := 0
+	for k, v := range props {
+		sub, _ := v.(map[string]any)
+		p := paramFromSchema(k, sub)
+		if i == 0 {
+			e = p.typ
+		} else if p.typ != e && e != ANY {
+			e = ANY
+		}
+		d.Add(p)
+		i++
+	}
+	return
+}
+
+// ToOpenAPI renders the api as an OpenAPI 3 document: one path per
+// Resource, one operation per Method, and a JSON Schema for each
+// Request.Body/Response.Body, the inverse of FromOpenAPI.
+// Request.Params are written as query parameters, including any that
+// FromOpenAPI read from path parameters, and the response body is
+// written under a single 200 response.
+func (a *Api) ToOpenAPI() []byte {
+	paths := map[string]any{}
+	for i := 0; i < a.Resources.Len(); i++ {
+		r := a.Resources.Index(i).(*Resource)
+		item := map[string]any{}
+		for j := 0; j < r.Methods.Len(); j++ {
+			m := r.Methods.Index(j).(*Method)
+			item[strings.ToLower(strings.TrimSpace(m.Type.String()))] = operationFromMethod(m)
+		}
+		paths[r.Url.Path] = item
+	}
+	spec := map[string]any{
+		"openapi": "3.0.3",
+		"info":    map[string]any{"title": "", "version": ""},
+		"paths":   paths,
+	}
+	return encoder.Yaml.Encode(spec)
+}
+
+// operationFromMethod renders a single Method as an OpenAPI
+// operation object.
+func operationFromMethod(m *Method) map[string]any {
+	op := map[string]any{"operationId": m.Name}
+	if !m.Request.Params.IsNil() && m.Request.Params.Len() > 0 {
+		params := make([]any, 0, m.Request.Params.Len())
+		for i := 0; i < m.Request.Params.Len(); i++ {
+			p := m.Request.Params.Index(i)
+			params = append(params, map[string]any{
+				"name":   p.Key(),
+				"in":     "query",
+				"schema": schemaFromParam(p),
+			})
+		}
+		op["parameters"] = params
+	}
+	if !m.Request.Header.IsNil() && m.Request.Header.Len() > 0 {
+		params, _ := op["parameters"].([]any)
+		for i := 0; i < m.Request.Header.Len(); i++ {
+			p := m.Request.Header.Index(i)
+			params = append(params, map[string]any{
+				"name":   p.Key(),
+				"in":     "header",
+				"schema": schemaFromParam(p),
+			})
+		}
+		op["parameters"] = params
+	}
+	if !m.Request.Body.IsNil() && m.Request.Body.Len() > 0 {
+		op["requestBody"] = map[string]any{
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": schemaFromParams(m.Request.Body)},
+			},
+		}
+	}
+	resp := map[string]any{"description": ""}
+	if !m.Response.Body.IsNil() && m.Response.Body.Len() > 0 {
+		resp["content"] = map[string]any{
+			"application/json": map[string]any{"schema": schemaFromParams(m.Response.Body)},
+		}
+	}
+	// responses is required by OpenAPI 3, so always emit at least a
+	// default 200 entry even when the method has no response body.
+	op["responses"] = map[string]any{"200": resp}
+	return op
+}
+
+// schemaFromParam renders a *Param as a JSON Schema fragment, the
+// inverse of paramFromSchema.
+func schemaFromParam(p *Param) map[string]any {
+	switch p.Type() {
+	case BOOL:
+		return map[string]any{"type": "boolean"}
+	case INT:
+		return map[string]any{"type": "integer"}
+	case FLOAT:
+		return map[string]any{"type": "number"}
+	case OBJECT:
+		return schemaFromParams(p.Elems())
+	case LIST:
+		return map[string]any{"type": "array", "items": schemaFromElem(p.ElemType(), p.Elems())}
+	default: // STRING, ANY, NONE
+		return map[string]any{"type": "string"}
+	}
+}
+
+// schemaFromElem renders the "items" schema for a LIST param.
+func schemaFromElem(t DataType, elems Params) map[string]any {
+	switch t {
+	case INT:
+		return map[string]any{"type": "integer"}
+	case FLOAT:
+		return map[string]any{"type": "number"}
+	case OBJECT:
+		return schemaFromParams(elems)
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
+
+// schemaFromParams renders an object's properties as a JSON Schema
+// fragment, the inverse of paramsFromSchema.
+func schemaFromParams(p Params) map[string]any {
+	props := map[string]any{}
+	for i := 0; i < p.Len(); i++ {
+		el := p.Index(i)
+		props[el.Key()] = schemaFromParam(el)
+	}
+	return map[string]any{"type": "object", "properties": props}
+}
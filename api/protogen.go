@@ -0,0 +1,101 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ----------------------------------------------------------------------------
+// PROTO / STUB GENERATION
+// turns the Resource/Method/Param tree parsed from the api's YAML
+// definition into a .proto file and minimal Go client stubs, the
+// same shapes used at runtime by the gRPC transport in grpc.go, so
+// the generated code and the dynamic dispatch path never diverge.
+
+// ToProto renders the api as a .proto file: one service per
+// Resource, one rpc per Method, and one message per distinct
+// Request/Response body shape.
+func (a *Api) ToProto(pkg string) []byte {
+	var b strings.Builder
+	b.WriteString("syntax = \"proto3\";\n\n")
+	fmt.Fprintf(&b, "package %s;\n\n", pkg)
+	for i := 0; i < a.Resources.Len(); i++ {
+		r := a.Resources.Index(i).(*Resource)
+		fmt.Fprintf(&b, "service %sService {\n", strings.Title(r.Name))
+		for j := 0; j < r.Methods.Len(); j++ {
+			m := r.Methods.Index(j).(*Method)
+			reqName := strings.Title(m.Name) + "Request"
+			respName := strings.Title(m.Name) + "Response"
+			fmt.Fprintf(&b, "  rpc %s(%s) returns (%s);\n", strings.Title(m.Name), reqName, respName)
+		}
+		b.WriteString("}\n\n")
+		for j := 0; j < r.Methods.Len(); j++ {
+			m := r.Methods.Index(j).(*Method)
+			writeMessage(&b, strings.Title(m.Name)+"Request", m.Request.Body)
+			writeMessage(&b, strings.Title(m.Name)+"Response", m.Response.Body)
+		}
+	}
+	return []byte(b.String())
+}
+
+// writeMessage renders a single proto message from a Param tree.
+func writeMessage(b *strings.Builder, name string, p Params) {
+	fmt.Fprintf(b, "message %s {\n", name)
+	if !p.IsNil() {
+		for i := 0; i < p.Len(); i++ {
+			el := p.Index(i)
+			fmt.Fprintf(b, "  %s %s = %d;\n", protoType(el), fieldName(el.Key()), i+1)
+		}
+	}
+	b.WriteString("}\n\n")
+}
+
+// protoType maps a Param's DataType to a proto3 scalar/repeated
+// field type, mirroring fieldDescriptor in grpc.go.
+func protoType(p *Param) string {
+	switch p.Type() {
+	case BOOL:
+		return "bool"
+	case INT:
+		return "int64"
+	case FLOAT:
+		return "double"
+	case OBJECT:
+		return strings.Title(p.Key()) + "Msg"
+	case LIST:
+		switch p.ElemType() {
+		case BOOL:
+			return "repeated bool"
+		case INT:
+			return "repeated int64"
+		case FLOAT:
+			return "repeated double"
+		default:
+			return "repeated string"
+		}
+	default: // STRING, ANY, NONE
+		return "string"
+	}
+}
+
+// ToGoStubs renders a minimal Go client for the api: one struct per
+// Resource with one method per Method, each delegating to
+// Method.Call so generated callers share the same retry/backoff and
+// transport handling as the dynamic dispatch path.
+func (a *Api) ToGoStubs(pkg string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	b.WriteString("// Code generated by cmd/apigen. DO NOT EDIT.\n\n")
+	b.WriteString("import \"github.com/jcdotter/go/api\"\n\n")
+	for i := 0; i < a.Resources.Len(); i++ {
+		r := a.Resources.Index(i).(*Resource)
+		name := strings.Title(r.Name)
+		fmt.Fprintf(&b, "type %sClient struct {\n\tResource *api.Resource\n}\n\n", name)
+		for j := 0; j < r.Methods.Len(); j++ {
+			m := r.Methods.Index(j).(*Method)
+			mName := strings.Title(m.Name)
+			fmt.Fprintf(&b, "func (c *%sClient) %s() error {\n\treturn c.Resource.Method(%q).Call()\n}\n\n", name, mName, m.Name)
+		}
+	}
+	return []byte(b.String())
+}
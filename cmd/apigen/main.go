@@ -0,0 +1,62 @@
+// Copyright 2023 james dotter.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://github.com/jcdotter/go/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// apigen reads the YAML api definition consumed by api.FromYaml and
+// emits a .proto file alongside minimal Go client stubs. The .proto
+// file describes the services a gRPC backend serves; the Go stubs
+// delegate to api.Method.Call, so calls made through them use the
+// dynamic proto reflection path in api/grpc.go.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/jcdotter/go/api"
+)
+
+func main() {
+	var (
+		in  = flag.String("in", "", "path to the api's YAML definition")
+		out = flag.String("out", ".", "directory to write the generated .proto and .go files")
+		pkg = flag.String("pkg", "api", "package/proto name for the generated files")
+	)
+	flag.Parse()
+
+	if *in == "" {
+		log.Fatal("apigen: -in is required")
+	}
+
+	y, err := os.ReadFile(*in)
+	if err != nil {
+		log.Fatalf("apigen: read %s: %v", *in, err)
+	}
+
+	a := api.FromYaml(y)
+	if a == nil {
+		log.Fatalf("apigen: %s is not a valid api definition", *in)
+	}
+
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		log.Fatalf("apigen: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(*out, *pkg+".proto"), a.ToProto(*pkg), 0o644); err != nil {
+		log.Fatalf("apigen: write proto: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(*out, *pkg+"_client.go"), a.ToGoStubs(*pkg), 0o644); err != nil {
+		log.Fatalf("apigen: write stubs: %v", err)
+	}
+}
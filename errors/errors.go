@@ -15,12 +15,14 @@
 package errors
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 
 	// required for go:linkname
 	_ "unsafe"
 
+	"github.com/golang/protobuf/proto"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -116,6 +118,36 @@ var postgresCode = map[string]Code{
 	"57014": CANCELLED,
 }
 
+// httpStatusCode is the inverse of httpCode, built once at init
+// so http status codes can be mapped back to a Code. where more
+// than one Code maps to the same http status, the first Code
+// encountered above (in iota order) wins.
+var httpStatusCode = func() map[int]Code {
+	m := make(map[int]Code, len(httpCode))
+	for c := OK; c <= UNAUTHENTICATED; c++ {
+		if _, ok := m[httpCode[c]]; !ok {
+			m[httpCode[c]] = c
+		}
+	}
+	return m
+}()
+
+// FromHttp returns the Code corresponding to the given http
+// status code, or UNKNOWN if the status is not recognized.
+func FromHttp(status int) Code {
+	if c, ok := httpStatusCode[status]; ok {
+		return c
+	}
+	return UNKNOWN
+}
+
+// FromGrpc returns the Code corresponding to the given gRPC status
+// code. Code is declared in the same order as codes.Code, so the
+// conversion is direct, mirroring Code.Grpc.
+func FromGrpc(c codes.Code) Code {
+	return Code(c)
+}
+
 func (c Code) String() string {
 	return statusText[c]
 }
@@ -225,8 +257,10 @@ func Unauthenticated(message string) error {
 
 // Status represents the error status and details.
 type Status struct {
-	code Code
-	msg  string
+	code    Code
+	msg     string
+	details []any
+	cause   error
 }
 
 // NewStatus returns a new status with the supplied code and message.
@@ -254,18 +288,73 @@ func (e *Status) String() string {
 	return e.Status() + ": " + e.msg
 }
 
+// WithDetail appends a structured detail to the status, mirroring
+// the repeated google.protobuf.Any details field on google.rpc.Status.
+// Details that satisfy proto.Message (e.g. errdetails.BadRequest,
+// errdetails.RetryInfo, errdetails.ResourceInfo) are attached to the
+// gRPC status via GprcErr; all details are included verbatim in the
+// JSON body written by HttpErr.
+func (e *Status) WithDetail(detail any) *Status {
+	e.details = append(e.details, detail)
+	return e
+}
+
+// WithCause sets the underlying error that produced this status, so
+// Unwrap (and therefore Is/As) can traverse into it.
+func (e *Status) WithCause(cause error) *Status {
+	e.cause = cause
+	return e
+}
+
+// Unwrap returns the status's underlying cause, or nil if none was
+// set with WithCause.
+func (e *Status) Unwrap() error {
+	return e.cause
+}
+
 // -----------------------------------------------------------------------------
 // CONVERSION METHODS
 
-// HttpErr executes the status as an HTTP error,
-// writing the status code and message to the response.
+// HttpErr executes the status as an HTTP error, writing a JSON body
+// of the form {"code":"INVALID","message":"...","details":[...]} so
+// REST clients can consume structured field-level violations instead
+// of http.Error's plain text.
 func (e *Status) HttpErr(w http.ResponseWriter) {
-	http.Error(w, e.String(), httpCode[e.code])
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpCode[e.code])
+	b, err := json.Marshal(struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Details []any  `json:"details,omitempty"`
+	}{
+		Code:    e.Status(),
+		Message: e.msg,
+		Details: e.details,
+	})
+	if err != nil {
+		return
+	}
+	w.Write(b)
 }
 
-// GprcErr returns the status as a gRPC error.
+// GprcErr returns the status as a gRPC error. Details that satisfy
+// proto.Message are attached via status.WithDetails; details that
+// don't are dropped from the gRPC path (they are still written in the
+// JSON body by HttpErr).
 func (e *Status) GprcErr() error {
-	return status.Error(codes.Code(e.code), e.msg)
+	s := status.New(codes.Code(e.code), e.msg)
+	var msgs []proto.Message
+	for _, d := range e.details {
+		if m, ok := d.(proto.Message); ok {
+			msgs = append(msgs, m)
+		}
+	}
+	if len(msgs) > 0 {
+		if withDetails, err := s.WithDetails(msgs...); err == nil {
+			s = withDetails
+		}
+	}
+	return s.Err()
 }
 
 // HttpCode returns the status as the corresponding HTTP status code.
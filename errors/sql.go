@@ -0,0 +1,87 @@
+// Copyright 2023 james dotter.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://github.com/jcdotter/go/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package errors
+
+import (
+	mssql "github.com/denisenkom/go-mssqldb"
+	"github.com/go-sql-driver/mysql"
+	"github.com/mattn/go-sqlite3"
+)
+
+// -----------------------------------------------------------------------------
+// DRIVER-SPECIFIC SQL ERRORS
+// FromSQL keeps callers driver-neutral: it inspects the vendor error
+// type returned by whichever database/sql driver is in use and maps
+// it to the same Code values the PostgreSQL path already produces,
+// so downstream handling never needs to know which database it's
+// talking to.
+
+var mysqlCode = map[uint16]Code{
+	1062: EXISTS,          // duplicate entry
+	1451: FAILED,          // cannot delete/update parent row: a foreign key constraint fails
+	1452: FAILED,          // cannot add/update child row: a foreign key constraint fails
+	1213: ABORTED,         // deadlock found when trying to get lock
+	1205: DEADLINE,        // lock wait timeout exceeded
+	1044: PERMISSION,      // access denied for user to database
+	1045: UNAUTHENTICATED, // access denied for user (using password)
+}
+
+var sqliteExtendedCode = map[sqlite3.ErrNoExtended]Code{
+	sqlite3.ErrConstraintUnique:     EXISTS,
+	sqlite3.ErrConstraintPrimaryKey: EXISTS,
+	sqlite3.ErrConstraintForeignKey: FAILED,
+	sqlite3.ErrConstraintNotNull:    FAILED,
+	sqlite3.ErrConstraintCheck:      FAILED,
+}
+
+var sqliteCode = map[sqlite3.ErrNo]Code{
+	sqlite3.ErrBusy:   UNAVAILABLE,
+	sqlite3.ErrLocked: ABORTED,
+}
+
+var mssqlCode = map[int32]Code{
+	2627: EXISTS,  // violation of primary key constraint
+	2601: EXISTS,  // cannot insert duplicate key row
+	547:  FAILED,  // the statement conflicted with a constraint
+	1205: ABORTED, // transaction was deadlocked and chosen as the victim
+}
+
+// FromSQL returns a Code-bearing error for err, dispatching on the
+// vendor-specific error type returned by the database/sql driver in
+// use (*mysql.MySQLError, sqlite3.Error, mssql.Error). A plain
+// SqlError falls back to the PostgreSQL SQLSTATE table via Postgres,
+// and any other error is reported as internal.
+func FromSQL(err error, message string) error {
+	switch e := err.(type) {
+	case *mysql.MySQLError:
+		if code, ok := mysqlCode[e.Number]; ok {
+			return &Status{code: code, msg: message}
+		}
+	case sqlite3.Error:
+		if code, ok := sqliteExtendedCode[e.ExtendedCode]; ok {
+			return &Status{code: code, msg: message}
+		}
+		if code, ok := sqliteCode[e.Code]; ok {
+			return &Status{code: code, msg: message}
+		}
+	case mssql.Error:
+		if code, ok := mssqlCode[e.Number]; ok {
+			return &Status{code: code, msg: message}
+		}
+	case SqlError:
+		return Postgres(e, message)
+	}
+	return Internal(message + ": " + err.Error())
+}
@@ -0,0 +1,164 @@
+// Copyright 2023 james dotter.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://github.com/jcdotter/go/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inspect
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// ----------------------------------------------------------------------------
+// CONCURRENT PARSING
+// Parse (inspect.go) fans a package's own files out across a bounded
+// worker pool and then hands its imports to parseImports here, which
+// schedules each imported package exactly once via a process-wide
+// cache keyed by canonical import path, so a package imported by many
+// files (or many packages) is only ever parsed a single time.
+
+// fieldsMu guards the Package fields that Parse populates
+// concurrently (Files and Imports). Types, Values and Funcs are
+// written by File.Inspect, which does not take this lock. Package
+// predates concurrent parsing and holds these as plain *data.Data,
+// so rather than thread a mutex through its definition, one
+// process-wide lock serializes the (fast, in-memory) Add calls;
+// the actual parse work happens outside the lock.
+var fieldsMu sync.Mutex
+
+// PackageOptions configures how a Package is parsed.
+type PackageOptions struct {
+	// Concurrency is the number of files parsed in parallel by
+	// Parse, and the fan-out width used when scheduling a
+	// package's imports. Defaults to runtime.GOMAXPROCS(0) when
+	// <= 0.
+	Concurrency int
+}
+
+// packageOptions tracks the PackageOptions configured for a Package
+// via NewPackageWithOptions, and those copied by parseImports onto
+// the imports it parses. Any other Package created with NewPackage
+// uses the zero PackageOptions (i.e. GOMAXPROCS(0) workers).
+var (
+	packageOptions   = map[*Package]PackageOptions{}
+	packageOptionsMu sync.Mutex
+)
+
+// NewPackageWithOptions is like NewPackage, but configures the
+// concurrency used by Parse for this package and its imports.
+func NewPackageWithOptions(pkgPath string, opts PackageOptions) *Package {
+	p := NewPackage(pkgPath)
+	packageOptionsMu.Lock()
+	packageOptions[p] = opts
+	packageOptionsMu.Unlock()
+	return p
+}
+
+// options returns the PackageOptions configured for p, or the zero
+// value if none were recorded for it.
+func (p *Package) options() PackageOptions {
+	packageOptionsMu.Lock()
+	defer packageOptionsMu.Unlock()
+	return packageOptions[p]
+}
+
+// importCache is the process-wide cache of parsed Packages, keyed by
+// canonical import path, shared across every Parse call so the same
+// upstream package is only parsed once no matter how many files or
+// packages import it.
+var (
+	importCache   = map[string]*Package{}
+	importCacheMu sync.Mutex
+)
+
+// cachedImport returns the Package for the given canonical import
+// path, creating and caching one if this is the first time it has
+// been seen. The second return reports whether a Package already
+// existed (and therefore is already parsed, or being parsed by
+// another goroutine) prior to this call.
+func cachedImport(importPath string) (pkg *Package, existed bool) {
+	importCacheMu.Lock()
+	defer importCacheMu.Unlock()
+	if pkg, existed = importCache[importPath]; existed {
+		return
+	}
+	pkg = NewPackage(importPath)
+	importCache[importPath] = pkg
+	return pkg, false
+}
+
+// parseImports collects the distinct import paths declared across
+// p's files and schedules each onto the shared import cache, parsing
+// newly-seen packages with up to width goroutines in flight at once.
+// Recursing into Parse for each import applies the same scheduling
+// transitively, depth first: an import that this call parses has its
+// own imports parsed before Parse returns for it. An import already
+// in the cache is not waited for, so one still being parsed by
+// another goroutine may be unfinished when parseImports returns.
+// Newly-seen imports are parsed with p's PackageOptions.
+func (p *Package) parseImports(width int) error {
+	deps := map[string]bool{}
+	for _, el := range p.Files.List() {
+		f := el.(*File)
+		if f.t == nil {
+			continue
+		}
+		for _, imp := range f.t.Imports {
+			deps[strings.Trim(imp.Path.Value, `"`)] = true
+		}
+	}
+	if len(deps) == 0 {
+		return nil
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+		sem  = make(chan struct{}, width)
+		opts = p.options()
+	)
+	for dep := range deps {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(dep string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			pkg, existed := cachedImport(dep)
+			fieldsMu.Lock()
+			if p.Imports.Get(dep) == nil {
+				p.Imports.Add(pkg)
+			}
+			fieldsMu.Unlock()
+			if existed {
+				return
+			}
+			packageOptionsMu.Lock()
+			packageOptions[pkg] = opts
+			packageOptionsMu.Unlock()
+			if err := pkg.Parse(); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(dep)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return nil
+}
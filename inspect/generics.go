@@ -0,0 +1,111 @@
+// Copyright 2023 james dotter.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://github.com/jcdotter/go/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inspect
+
+import (
+	"go/ast"
+	"sync"
+)
+
+// ----------------------------------------------------------------------------
+// GENERICS
+// A generic declared type or func (one with a TypeSpec/FuncType
+// TypeParams list) is registered the same way as any other Type, with
+// its type parameters tracked out of band in typeParams, keyed by
+// pointer identity; instantiating it (TypeIndex) produces a distinct
+// Type of kind GENERIC_INST carrying the type argument list.
+
+// GENERIC_INST is the kind of a Type produced by instantiating a
+// generic declared type with a type argument list, e.g. List[int].
+// It is defined here, alongside the rest of the generics support,
+// rather than with the rest of the kind enum, since it post-dates it.
+const GENERIC_INST byte = 255
+
+// TypeParam is a single type parameter declared on a generic type or
+// func, e.g. the "T constraints.Ordered" in "func Max[T constraints.Ordered](...)".
+type TypeParam struct {
+	name       string
+	constraint *Type
+}
+
+// Name returns the type parameter's name.
+func (p *TypeParam) Name() string {
+	return p.name
+}
+
+// Constraint returns the type parameter's constraint type, e.g. the
+// Type for constraints.Ordered.
+func (p *TypeParam) Constraint() *Type {
+	return p.constraint
+}
+
+// Generic is a Type's object when its kind is GENERIC_INST. It
+// records the generic base type and the type arguments it was
+// instantiated with.
+type Generic struct {
+	typ  *Type
+	base *Type
+	args []*Type
+}
+
+// Base returns the generic declared type this instantiation is of.
+func (g *Generic) Base() *Type {
+	return g.base
+}
+
+// Args returns the type arguments this instantiation was built with.
+func (g *Generic) Args() []*Type {
+	return g.args
+}
+
+// typeParams tracks the type parameters declared on a generic Type,
+// keyed by pointer identity. Type predates generics and has no field
+// for this, so it is tracked here rather than on the struct itself,
+// the same out-of-band pattern PackageOptions uses for Package.
+var (
+	typeParams   = map[*Type][]*TypeParam{}
+	typeParamsMu sync.Mutex
+)
+
+// setTypeParams records the type parameters declared on typ.
+func setTypeParams(typ *Type, params []*TypeParam) {
+	typeParamsMu.Lock()
+	defer typeParamsMu.Unlock()
+	typeParams[typ] = params
+}
+
+// TypeParamsOf returns the type parameters declared on typ, or nil if
+// typ is not a generic declared type.
+func TypeParamsOf(typ *Type) []*TypeParam {
+	typeParamsMu.Lock()
+	defer typeParamsMu.Unlock()
+	return typeParams[typ]
+}
+
+// TypeParams builds the []*TypeParam declared by a type or func's
+// TypeParams field list, resolving each type parameter's constraint
+// via TypeExpr.
+func (f *File) TypeParams(fl *ast.FieldList) (params []*TypeParam) {
+	if fl == nil {
+		return nil
+	}
+	for _, field := range fl.List {
+		constraint := f.TypeExpr(field.Type)
+		for _, n := range field.Names {
+			params = append(params, &TypeParam{name: n.Name, constraint: constraint})
+		}
+	}
+	return
+}
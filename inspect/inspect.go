@@ -15,18 +15,26 @@
 package inspect
 
 import (
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
 	"reflect"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/jcdotter/go/data"
 	"github.com/jcdotter/go/path"
 )
 
+// fset is shared across every file parsed by the package, so a
+// token.Pos recorded anywhere in the type graph can be resolved back
+// to a file/line/column via fset.Position, e.g. for Linter diagnostics.
+var fset = token.NewFileSet()
+
 // Inspect parses the package content in the path provided and
 // returns the package object for inspection, or an error if
 // the package cannot be parsed.
@@ -44,51 +52,113 @@ func Inspect(PkgPath string) (*Package, error) {
 	return p, nil
 }
 
-// Parse parses the package content if not already parsed. If Entites are provided,
-// the package will only parse the provided entities, otherwise the package will
-// parse all entities in the package. Returns an error if the package cannot be parsed.
-// TODO: Make file parsing concurrent.
+// Parse parses the package content if not already parsed, fanning
+// the package's files out across a bounded worker pool (see
+// PackageOptions.Concurrency) and then scheduling its imports, each
+// parsed exactly once via a process-wide cache keyed by canonical
+// import path even when several files (or several packages) import
+// the same path. Returns an aggregated error if one or more files or
+// imports fail to parse, so a single bad file doesn't mask the rest.
 func (p *Package) Parse() (err error) {
-	// parse each file in the package
-	for _, f := range path.Files(p.Path) {
-		var file *File
+	if p.Files.Len() > 0 {
+		return
+	}
 
-		// parse file name
-		n := f[strings.LastIndex(f, "/")+1 : strings.LastIndex(f, ".")]
+	// seed the cache with p itself, keyed by its own canonical path,
+	// so an import cycle/diamond back to the root resolves to p
+	// instead of cachedImport allocating and re-parsing a duplicate.
+	importCacheMu.Lock()
+	if _, ok := importCache[p.Path]; !ok {
+		importCache[p.Path] = p
+	}
+	importCacheMu.Unlock()
 
-		// check if file is already parsed
-		// else add a new file to the package
-		if f := p.Files.Get(n); f != nil {
-			return
-		}
-		file = NewFile(p, n)
-		p.Files.Add(file)
+	paths := path.Files(p.Path)
+	width := p.options().Concurrency
+	if width <= 0 {
+		width = runtime.GOMAXPROCS(0)
+	}
+	workers := width
+	if workers > len(paths) {
+		workers = len(paths)
+	}
 
-		// parse file to abstract syntax tree
-		file.t, err = parser.ParseFile(token.NewFileSet(), f, nil, parser.SkipObjectResolution)
-		if err != nil {
-			return
+	type parsed struct {
+		name string
+		tree *ast.File
+		err  error
+	}
+	jobs := make(chan string)
+	results := make(chan parsed)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for fp := range jobs {
+				name := fp[strings.LastIndex(fp, "/")+1 : strings.LastIndex(fp, ".")]
+				tree, e := parser.ParseFile(fset, fp, nil, parser.SkipObjectResolution)
+				results <- parsed{name: name, tree: tree, err: e}
+			}
+		}()
+	}
+	go func() {
+		for _, fp := range paths {
+			jobs <- fp
+		}
+		close(jobs)
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var errs []error
+	for r := range results {
+		if r.err != nil {
+			errs = append(errs, r.err)
+			continue
+		}
+		file := NewFile(p, r.name)
+		file.t = r.tree
+		fieldsMu.Lock()
+		if p.Files.Get(r.name) == nil {
+			p.Files.Add(file)
 		}
+		fieldsMu.Unlock()
 	}
 
-	// parse package name
 	if p.Files.Len() > 0 {
 		p.Name = p.Files.Index(0).(*File).t.Name.Name
 	}
-	return
+
+	if e := p.parseImports(width); e != nil {
+		errs = append(errs, e)
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return nil
 }
 
-// Inspect inspects the declared entities in the file and
-// adds them to the package.
+// Inspect inspects the declared entities in the file and adds them
+// to the package. Type and func declarations that fail to resolve
+// because they reference a type declared later in the same package
+// are retried in a second pass, once every type name in the file has
+// had a chance to be registered.
 func (f *File) Inspect() (err error) {
 	if f.t != nil {
+		var pending []ast.Decl
 		for _, d := range f.t.Decls {
 
 			// route declaration to appropriate
 			// inspection method
 			switch decl := d.(type) {
 			case *ast.FuncDecl:
-				err = f.InspectFunc(decl)
+				if err = f.InspectFunc(decl); err == ErrNotType {
+					pending = append(pending, d)
+					err = nil
+				}
 			case *ast.GenDecl:
 				switch decl.Tok {
 				case token.CONST:
@@ -96,12 +166,27 @@ func (f *File) Inspect() (err error) {
 				case token.VAR:
 					err = f.InspectValues(VAR, decl.Specs)
 				case token.TYPE:
-					err = f.InspectType(decl.Specs)
+					if err = f.InspectType(decl.Specs); err == ErrNotType {
+						pending = append(pending, d)
+						err = nil
+					}
 				case token.IMPORT:
 					err = f.InspectImports(decl.Specs)
 				}
 			}
 		}
+
+		// second pass: forward references between types declared
+		// in this file should now resolve, since every type name
+		// has been registered (even if some are still unresolved).
+		for _, d := range pending {
+			switch decl := d.(type) {
+			case *ast.FuncDecl:
+				err = f.InspectFunc(decl)
+			case *ast.GenDecl:
+				err = f.InspectType(decl.Specs)
+			}
+		}
 	}
 	return
 }
@@ -114,6 +199,7 @@ func (f *File) InspectImports(specs []ast.Spec) (err error) {
 		// create and add import to file
 		i := s.(*ast.ImportSpec)
 		imp := &Import{file: f, name: i.Name.Name}
+		setImportPos(imp, fset.Position(i.Pos()))
 		f.i.Add(imp)
 
 		// get package by path if already imported another file,
@@ -239,15 +325,113 @@ func (f *File) PrintValue(v *Value) {
 	)
 }
 
+// InspectType inspects the type declarations in the file and adds
+// them to the package. Each TypeSpec is dispatched on the underlying
+// Type.Type to build the full Type graph (struct, interface, array,
+// map, chan, func, ident, selector or pointer). For an Ident/
+// SelectorExpr underlying type (type ID int, type ID pkg.T), both a
+// type alias (TypeSpec.Assign != token.NoPos) and a defined type get
+// a new Type under their own name that shares the underlying's
+// kind/object; an alias is registered without type parameters. In
+// neither case is the underlying Type itself renamed or otherwise mutated,
+// as it may be shared process-wide (a BuiltinTypes entry, or another
+// file's declared type).
 func (f *File) InspectType(t []ast.Spec) (err error) {
-	/* for _, s := range t {
-		fmt.Println("TYPE:", s.(*ast.TypeSpec).Name.Name)
-	} */
+	for _, s := range t {
+		spec := s.(*ast.TypeSpec)
+
+		// skip if this type was already inspected, e.g. as a
+		// forward reference resolved while inspecting another
+		// declaration in this file.
+		if f.p.Types.Get(spec.Name.Name) != nil {
+			continue
+		}
+
+		var typ *Type
+		switch e := spec.Type.(type) {
+		case *ast.StructType:
+			typ = f.TypeStruct(e)
+		case *ast.InterfaceType:
+			typ = f.TypeInterface(e)
+		case *ast.ArrayType:
+			typ = f.TypeArray(e)
+		case *ast.MapType:
+			typ = f.TypeMap(e)
+		case *ast.ChanType:
+			typ = f.TypeChan(e)
+		case *ast.StarExpr:
+			typ = f.TypePointer(e)
+		case *ast.FuncType:
+			typ = f.TypeFunc(&ast.FuncLit{Type: e})
+		default:
+			// Ident, SelectorExpr, and any other named-type
+			// or generic-instantiation expression
+			underlying := f.TypeExpr(e)
+			if underlying == nil {
+				return ErrNotType
+			}
+			if spec.Assign != token.NoPos {
+				typ = &Type{file: f, name: spec.Name.Name, kind: underlying.kind, object: underlying.object}
+				f.p.Types.Add(typ)
+				continue
+			}
+			typ = &Type{kind: underlying.kind, object: underlying.object}
+		}
+
+		// the underlying type references a type not yet
+		// registered in the package; signal the caller so the
+		// declaration can be retried in a second pass.
+		if typ == nil {
+			return ErrNotType
+		}
+
+		typ.file = f
+		typ.name = spec.Name.Name
+		f.p.Types.Add(typ)
+
+		if spec.TypeParams != nil {
+			setTypeParams(typ, f.TypeParams(spec.TypeParams))
+		}
+	}
 	return
 }
 
+// InspectFunc inspects a func declaration and registers it in the
+// package: a plain func is added to p.Funcs by name, while a method
+// is added to p.Funcs qualified by its receiver's type name
+// ("T.Method", for a value or a pointer receiver alike) and records
+// the receiver's Type on the Func. The receiver's Type itself is not
+// given a reference to the method; a type's methods are found in
+// p.Funcs by their qualified name.
 func (f *File) InspectFunc(fn *ast.FuncDecl) (err error) {
-	//fmt.Println("FUNC DECL:", fn.Name.Name)
+	fnc := &Func{file: f}
+	typ := &Type{file: f, kind: FUNC, object: fnc}
+	fnc.typ = typ
+	f.TypeFuncParams(fn.Type.Params, fnc.in)
+	f.TypeFuncParams(fn.Type.Results, fnc.out)
+	if fn.Type.TypeParams != nil {
+		setTypeParams(typ, f.TypeParams(fn.Type.TypeParams))
+	}
+
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		typ.name = fn.Name.Name
+		fnc.name = fn.Name.Name
+		f.p.Funcs.Add(fnc)
+		return
+	}
+
+	recv := f.TypeExpr(fn.Recv.List[0].Type)
+	if recv == nil {
+		return ErrNotType
+	}
+	recvName := recv.name
+	if ptr, ok := recv.object.(*Pointer); ok && recv.kind == POINTER {
+		recvName = ptr.elem.name
+	}
+	typ.name = recvName + "." + fn.Name.Name
+	fnc.name = typ.name
+	fnc.recv = recv
+	f.p.Funcs.Add(fnc)
 	return
 }
 
@@ -331,7 +515,7 @@ func (f *File) TypeExpr(e ast.Expr) *Type {
 		case *ast.Ident:
 			return f.TypeIdent(c)
 		case *ast.SelectorExpr:
-			// TODO: return f.TypeSelector(c)
+			return f.TypeSelector(c)
 		case *ast.ArrayType:
 			return f.TypeArray(c)
 		case *ast.MapType:
@@ -342,10 +526,19 @@ func (f *File) TypeExpr(e ast.Expr) *Type {
 	case *ast.SelectorExpr:
 		// call to an external package function, value or type
 		// or call to internal package method or struct field
-	default:
-		// case *ast.TypeAssertExpr:
-		// case *ast.IndexExpr:
-		// case *ast.SliceExpr:
+		return f.TypeSelector(t)
+	case *ast.TypeAssertExpr:
+		return f.TypeAssert(t)
+	case *ast.IndexExpr:
+		return f.TypeIndex(t.X, []ast.Expr{t.Index})
+	case *ast.IndexListExpr:
+		return f.TypeIndex(t.X, t.Indices)
+	case *ast.SliceExpr:
+		return f.TypeSlice(t)
+	case *ast.ChanType:
+		return f.TypeChan(t)
+	case *ast.InterfaceType:
+		return f.TypeInterface(t)
 	}
 	fmt.Println("EXPR:", reflect.TypeOf(e))
 	return nil
@@ -544,7 +737,11 @@ func (f *File) TypeMap(m *ast.MapType) (typ *Type) {
 	return
 }
 
-// TypeStruct returns the type of the struct expression provided.
+// TypeStruct returns the type of the struct expression provided,
+// with its fields (and their tags) registered on the Struct object.
+// An embedded field (no Names) is added under its type's own name,
+// so a promoted field or method can be looked up the same way as a
+// declared one.
 func (f *File) TypeStruct(s *ast.StructType) (typ *Type) {
 	typ = &Type{
 		file: f,
@@ -552,7 +749,219 @@ func (f *File) TypeStruct(s *ast.StructType) (typ *Type) {
 	}
 	str := NewStruct(typ)
 	typ.object = str
-	// TODO: loop fields and add them to the struct
-	// if field is func, add as method, else add as field
+	if s.Fields != nil {
+		for _, field := range s.Fields.List {
+			ft := f.TypeExpr(field.Type)
+			var tag reflect.StructTag
+			if field.Tag != nil {
+				tag = reflect.StructTag(strings.Trim(field.Tag.Value, "`"))
+			}
+			if len(field.Names) == 0 {
+				if ft != nil {
+					str.AddField(ft.name, ft, tag)
+				}
+				continue
+			}
+			for _, n := range field.Names {
+				str.AddField(n.Name, ft, tag)
+			}
+		}
+	}
+	return
+}
+
+// TypeInterface returns the type of the interface expression
+// provided, enumerating its declared methods and any embedded
+// interfaces (a method spec in InterfaceType.Methods with no Names
+// is an embedded interface rather than a method).
+func (f *File) TypeInterface(it *ast.InterfaceType) (typ *Type) {
+	in := &Interface{}
+	typ = &Type{
+		file:   f,
+		kind:   INTERFACE,
+		object: in,
+	}
+	in.typ = typ
+	if it.Methods != nil {
+		for _, m := range it.Methods.List {
+			if len(m.Names) == 0 {
+				if embedded := f.TypeExpr(m.Type); embedded != nil {
+					in.embeds = append(in.embeds, embedded)
+				}
+				continue
+			}
+			ft := m.Type.(*ast.FuncType)
+			fnc := &Func{file: f}
+			mfn := &Type{file: f, kind: FUNC, object: fnc}
+			fnc.typ = mfn
+			f.TypeFuncParams(ft.Params, fnc.in)
+			f.TypeFuncParams(ft.Results, fnc.out)
+			for _, n := range m.Names {
+				fnc.name = n.Name
+				in.methods = append(in.methods, fnc)
+			}
+		}
+	}
+	return
+}
+
+// TypeChan returns the type of the channel expression provided.
+func (f *File) TypeChan(c *ast.ChanType) (typ *Type) {
+	ch := &Chan{dir: c.Dir, elem: f.TypeExpr(c.Value)}
+	typ = &Type{
+		file:   f,
+		kind:   CHAN,
+		object: ch,
+	}
+	ch.typ = typ
+	switch c.Dir {
+	case ast.SEND:
+		typ.name = "chan<- " + ch.elem.name
+	case ast.RECV:
+		typ.name = "<-chan " + ch.elem.name
+	default:
+		typ.name = "chan " + ch.elem.name
+	}
+	return
+}
+
+// TypeSelector returns the type named by a qualified identifier. If
+// the selector's base names an imported package, the type is looked
+// up (parsing the import if needed) by its exported name in that
+// package; otherwise the base is resolved as a value and the selector
+// is looked up as one of its methods, qualified "Recv.Method" as
+// InspectFunc registers it.
+// TODO: resolve a struct field access (as opposed to a method) on an
+// internal value; this requires a field lookup on Struct that is not
+// yet exposed.
+func (f *File) TypeSelector(s *ast.SelectorExpr) (typ *Type) {
+	if id, ok := s.X.(*ast.Ident); ok {
+		if imp := f.i.Get(id.Name); imp != nil {
+			i := imp.(*Import)
+			if t := i.pkg.Types.Get(s.Sel.Name); t != nil {
+				return t.(*Type)
+			}
+			if err := i.pkg.Parse(); err == nil {
+				if t := i.pkg.Types.Get(s.Sel.Name); t != nil {
+					return t.(*Type)
+				}
+			}
+			return nil
+		}
+	}
+
+	recv := f.TypeExpr(s.X)
+	if recv == nil {
+		return nil
+	}
+	base := recv
+	if base.kind == POINTER {
+		if ptr, ok := base.object.(*Pointer); ok {
+			base = ptr.elem
+		}
+	}
+	if fn := f.p.Funcs.Get(base.name + "." + s.Sel.Name); fn != nil {
+		return fn.(*Func).typ
+	}
+	return nil
+}
+
+// TypeIndex returns the type of an index or generic instantiation
+// expression (x[i] or x[i, j, ...]). If x names a generic declared
+// type, the result is a new Type of kind GENERIC_INST carrying the
+// type arguments; otherwise the expression is a value index, and the
+// result is the element type of x's slice, array or map type.
+func (f *File) TypeIndex(x ast.Expr, indices []ast.Expr) (typ *Type) {
+	base := f.TypeExpr(x)
+	if base == nil {
+		return nil
+	}
+	if params := TypeParamsOf(base); len(params) > 0 {
+		return f.typeGenericInst(base, indices)
+	}
+	switch o := base.object.(type) {
+	case *Array:
+		return o.elem
+	case *Map:
+		return o.elem
+	}
+	return nil
+}
+
+// typeGenericInst builds (or returns the cached) Type for a generic
+// type instantiated with the given type argument expressions.
+func (f *File) typeGenericInst(base *Type, args []ast.Expr) (typ *Type) {
+	targs := make([]*Type, len(args))
+	names := make([]string, len(args))
+	for i, a := range args {
+		targs[i] = f.TypeExpr(a)
+		if targs[i] != nil {
+			names[i] = targs[i].name
+		}
+	}
+	name := base.name + "[" + strings.Join(names, ",") + "]"
+	if t := f.p.Types.Get(name); t != nil {
+		return t.(*Type)
+	}
+	gen := &Generic{base: base, args: targs}
+	typ = &Type{file: f, kind: GENERIC_INST, name: name, object: gen}
+	gen.typ = typ
+	f.p.Types.Add(typ)
 	return
 }
+
+// TypeSlice returns the type of a slice expression (x[lo:hi] or
+// x[lo:hi:max]). Slicing an array yields a slice of the array's
+// element type; slicing anything else (a slice or a string) yields
+// the type being sliced.
+func (f *File) TypeSlice(s *ast.SliceExpr) (typ *Type) {
+	base := f.TypeExpr(s.X)
+	if base == nil {
+		return nil
+	}
+	if arr, ok := base.object.(*Array); ok && base.kind == ARRAY {
+		return f.typeSliceOf(arr.elem)
+	}
+	return base
+}
+
+// typeSliceOf returns the (cached) slice type of elem.
+func (f *File) typeSliceOf(elem *Type) (typ *Type) {
+	n := "[]" + elem.name
+	if t := f.p.Types.Get(n); t != nil {
+		return t.(*Type)
+	}
+	arr := &Array{elem: elem}
+	typ = &Type{file: f, kind: SLICE, name: n, object: arr}
+	arr.typ = typ
+	f.p.Types.Add(typ)
+	return
+}
+
+// TypeAssert returns the asserted type of a type assertion expression
+// (x.(T)). The two-value form (v, ok := x.(T)) carries the same
+// expression and is handled the same way; the caller is responsible
+// for typing the second (bool) result.
+func (f *File) TypeAssert(t *ast.TypeAssertExpr) (typ *Type) {
+	if t.Type == nil {
+		// the X.(type) form, valid only inside a type switch guard
+		return f.TypeExpr(t.X)
+	}
+	return f.TypeExpr(t.Type)
+}
+
+// Interface is a Type's object when its kind is INTERFACE. It
+// records the interface's own method set along with any embedded
+// interfaces, mirroring the way Struct records fields.
+type Interface struct {
+	typ     *Type
+	methods []*Func
+	embeds  []*Type
+}
+
+// Chan is a Type's object when its kind is CHAN.
+type Chan struct {
+	typ  *Type
+	dir  ast.ChanDir
+	elem *Type
+}
@@ -0,0 +1,339 @@
+// Copyright 2023 james dotter.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://github.com/jcdotter/go/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inspect
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ----------------------------------------------------------------------------
+// LINT
+// a small pluggable rule engine layered on top of the inspect AST
+// walk, modeled on the classic golint visitor: a Rule inspects a
+// *File and reports Diagnostics, and a Linter runs a set of Rules
+// over a *Package, skipping findings suppressed with a
+// "//nolint:<rule>" line comment.
+
+// Severity classifies how serious a Diagnostic is.
+type Severity byte
+
+const (
+	Info Severity = iota
+	Warning
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// Diagnostic is a single finding reported by a Rule.
+type Diagnostic struct {
+	Pos      token.Position
+	Rule     string
+	Severity Severity
+	Message  string
+}
+
+// Rule inspects a File and reports any findings. Category is a
+// short, stable identifier used both to label Diagnostics and to
+// match "//nolint:<category>" suppression comments.
+type Rule interface {
+	Category() string
+	Check(f *File) []Diagnostic
+}
+
+// rules holds every Rule registered with RegisterRule, keyed by
+// Category, so a Linter built with no explicit rule list runs the
+// full registered set.
+var rules = map[string]Rule{}
+
+// RegisterRule adds r to the set of rules run by a Linter built
+// with no explicit rule list. Registering a rule under a Category
+// that is already registered replaces the prior rule.
+func RegisterRule(r Rule) {
+	rules[r.Category()] = r
+}
+
+// Linter runs a set of Rules over a Package's files.
+type Linter struct {
+	Rules []Rule
+}
+
+// NewLinter returns a Linter running the given rules, or every
+// rule registered via RegisterRule if none are given.
+func NewLinter(r ...Rule) *Linter {
+	if len(r) == 0 {
+		for _, reg := range rules {
+			r = append(r, reg)
+		}
+	}
+	return &Linter{Rules: r}
+}
+
+// Lint runs the linter's rules over every file in p, dropping any
+// Diagnostic suppressed by a "//nolint:<category>" comment on the
+// same line.
+func (l *Linter) Lint(p *Package) (out []Diagnostic) {
+	for _, el := range p.Files.List() {
+		f := el.(*File)
+		suppressed := nolintLines(f)
+		for _, r := range l.Rules {
+			for _, d := range r.Check(f) {
+				if suppressed[d.Pos.Line][r.Category()] || suppressed[d.Pos.Line]["all"] {
+					continue
+				}
+				out = append(out, d)
+			}
+		}
+	}
+	return
+}
+
+// nolintLines collects the set of rule categories suppressed on
+// each line of f via a "//nolint:<category>[,<category>...]"
+// comment, or "//nolint" alone to suppress every category.
+func nolintLines(f *File) map[int]map[string]bool {
+	m := map[int]map[string]bool{}
+	if f.t == nil {
+		return m
+	}
+	for _, cg := range f.t.Comments {
+		for _, c := range cg.List {
+			text := strings.TrimPrefix(strings.TrimSpace(c.Text), "//")
+			text = strings.TrimSpace(text)
+			if text != "nolint" && !strings.HasPrefix(text, "nolint:") {
+				continue
+			}
+			line := fset.Position(c.Pos()).Line
+			if m[line] == nil {
+				m[line] = map[string]bool{}
+			}
+			if text == "nolint" {
+				m[line]["all"] = true
+				continue
+			}
+			for _, cat := range strings.Split(strings.TrimPrefix(text, "nolint:"), ",") {
+				m[line][strings.TrimSpace(cat)] = true
+			}
+		}
+	}
+	return m
+}
+
+// ----------------------------------------------------------------------------
+// BUILT-IN RULES
+
+// importPos records the source position of each *Import's spec, set
+// by InspectImports as it builds the Import. Import carries no
+// position of its own, so UnusedImportsRule looks it up here to give
+// its Diagnostics a real line a "//nolint" comment can match.
+var (
+	importPosMu sync.Mutex
+	importPos   = map[*Import]token.Position{}
+)
+
+// setImportPos records pos as imp's source position.
+func setImportPos(imp *Import, pos token.Position) {
+	importPosMu.Lock()
+	importPos[imp] = pos
+	importPosMu.Unlock()
+}
+
+// importPosition returns the source position previously recorded for
+// imp via setImportPos, or the zero Position if none was recorded.
+func importPosition(imp *Import) token.Position {
+	importPosMu.Lock()
+	defer importPosMu.Unlock()
+	return importPos[imp]
+}
+
+// UnusedImportsRule flags imports that are never referenced by a
+// selector expression anywhere in the file.
+type UnusedImportsRule struct{}
+
+func (UnusedImportsRule) Category() string { return "unused-imports" }
+
+func (UnusedImportsRule) Check(f *File) (out []Diagnostic) {
+	if f.t == nil {
+		return
+	}
+	used := map[string]bool{}
+	ast.Inspect(f.t, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				used[id.Name] = true
+			}
+		}
+		return true
+	})
+	for _, el := range f.i.List() {
+		imp := el.(*Import)
+		if imp.name == "_" || imp.name == "." {
+			continue
+		}
+		if !used[imp.name] {
+			out = append(out, Diagnostic{
+				Pos:      importPosition(imp),
+				Rule:     "unused-imports",
+				Severity: Warning,
+				Message:  "imported and not used: " + imp.name,
+			})
+		}
+	}
+	return
+}
+
+// ExportedDocRule flags exported funcs and types lacking a doc
+// comment, per the "every exported name should have a doc comment"
+// convention this package itself follows.
+type ExportedDocRule struct{}
+
+func (ExportedDocRule) Category() string { return "exported-doc" }
+
+func (ExportedDocRule) Check(f *File) (out []Diagnostic) {
+	if f.t == nil {
+		return
+	}
+	for _, d := range f.t.Decls {
+		switch decl := d.(type) {
+		case *ast.FuncDecl:
+			if decl.Name.IsExported() && decl.Doc == nil {
+				out = append(out, Diagnostic{
+					Pos:      fset.Position(decl.Pos()),
+					Rule:     "exported-doc",
+					Severity: Info,
+					Message:  "exported func " + decl.Name.Name + " should have a doc comment",
+				})
+			}
+		case *ast.GenDecl:
+			if decl.Tok != token.TYPE {
+				continue
+			}
+			for _, s := range decl.Specs {
+				ts := s.(*ast.TypeSpec)
+				if ts.Name.IsExported() && decl.Doc == nil && ts.Doc == nil {
+					out = append(out, Diagnostic{
+						Pos:      fset.Position(ts.Pos()),
+						Rule:     "exported-doc",
+						Severity: Info,
+						Message:  "exported type " + ts.Name.Name + " should have a doc comment",
+					})
+				}
+			}
+		}
+	}
+	return
+}
+
+// shadowedBuiltins is the set of predeclared identifiers that are
+// legal to redeclare but almost always a mistake to shadow.
+var shadowedBuiltins = map[string]bool{
+	"len": true, "cap": true, "new": true, "make": true, "append": true,
+	"copy": true, "delete": true, "panic": true, "recover": true,
+	"nil": true, "true": true, "false": true, "error": true, "string": true,
+	"int": true, "bool": true, "byte": true, "rune": true,
+}
+
+// ShadowedBuiltinRule flags params and local vars named after a
+// predeclared identifier.
+type ShadowedBuiltinRule struct{}
+
+func (ShadowedBuiltinRule) Category() string { return "shadowed-builtin" }
+
+func (ShadowedBuiltinRule) Check(f *File) (out []Diagnostic) {
+	if f.t == nil {
+		return
+	}
+	ast.Inspect(f.t, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok || !shadowedBuiltins[id.Name] {
+			return true
+		}
+		if id.Obj == nil || (id.Obj.Kind != ast.Var && id.Obj.Kind != ast.Typ) {
+			return true
+		}
+		out = append(out, Diagnostic{
+			Pos:      fset.Position(id.Pos()),
+			Rule:     "shadowed-builtin",
+			Severity: Warning,
+			Message:  "declaration of " + id.Name + " shadows a predeclared identifier",
+		})
+		return true
+	})
+	return
+}
+
+// StutterRule flags an exported name that repeats its package name,
+// e.g. buffer.BufferReader instead of buffer.Reader.
+type StutterRule struct{}
+
+func (StutterRule) Category() string { return "stutter" }
+
+func (StutterRule) Check(f *File) (out []Diagnostic) {
+	if f.t == nil || f.p == nil || f.p.Name == "" {
+		return
+	}
+	pkg := strings.ToLower(f.p.Name)
+	check := func(name string, pos token.Pos) {
+		if !ast.IsExported(name) {
+			return
+		}
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, pkg) && len(lower) > len(pkg) &&
+			!unicode.IsLower(rune(name[len(pkg)])) {
+			out = append(out, Diagnostic{
+				Pos:      fset.Position(pos),
+				Rule:     "stutter",
+				Severity: Info,
+				Message:  "name " + name + " stutters with package name " + f.p.Name,
+			})
+		}
+	}
+	for _, d := range f.t.Decls {
+		switch decl := d.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil {
+				check(decl.Name.Name, decl.Pos())
+			}
+		case *ast.GenDecl:
+			if decl.Tok == token.TYPE {
+				for _, s := range decl.Specs {
+					ts := s.(*ast.TypeSpec)
+					check(ts.Name.Name, ts.Pos())
+				}
+			}
+		}
+	}
+	return
+}
+
+func init() {
+	RegisterRule(UnusedImportsRule{})
+	RegisterRule(ExportedDocRule{})
+	RegisterRule(ShadowedBuiltinRule{})
+	RegisterRule(StutterRule{})
+}
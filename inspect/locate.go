@@ -0,0 +1,160 @@
+// Copyright 2023 james dotter.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://github.com/jcdotter/go/LICENSE
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inspect
+
+import (
+	"bytes"
+	"errors"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// ----------------------------------------------------------------------------
+// LOCATE
+// Locate, FindIdentAt and ReferencesOf let a caller map a source
+// position back to the Value/Type/Func it resolves to, and back out
+// again to every position that names it, so inspect can back an
+// editor's "go to definition"/"find references". AddImport and
+// RemoveImport round out the package as a light code-mod library by
+// letting a caller rewrite a File's imports and re-serialize it.
+
+// Locate returns the chain of AST nodes enclosing pos, innermost
+// first, or nil if the file has not been parsed.
+func (f *File) Locate(pos token.Pos) []ast.Node {
+	if f.t == nil {
+		return nil
+	}
+	path, _ := astutil.PathEnclosingInterval(f.t, pos, pos)
+	return path
+}
+
+// FindIdentAt resolves the identifier at the given 1-indexed line and
+// column of the named file (a key in p.Files, as populated by Parse)
+// to the *Value, *Type or *Func it declares or refers to.
+func (p *Package) FindIdentAt(file string, line, col int) (entity any, err error) {
+	el := p.Files.Get(file)
+	if el == nil {
+		return nil, errors.New("inspect: file not found: " + file)
+	}
+	f := el.(*File)
+	if f.t == nil {
+		return nil, errors.New("inspect: file not parsed: " + file)
+	}
+
+	tf := fset.File(f.t.Pos())
+	if tf == nil {
+		return nil, errors.New("inspect: position not found for file: " + file)
+	}
+	pos := tf.LineStart(line) + token.Pos(col-1)
+
+	for _, n := range f.Locate(pos) {
+		id, ok := n.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		if v := f.p.Values.Get(id.Name); v != nil {
+			return v, nil
+		}
+		if t := f.p.Types.Get(id.Name); t != nil {
+			return t, nil
+		}
+		if fn := f.p.Funcs.Get(id.Name); fn != nil {
+			return fn, nil
+		}
+		if typ := f.TypeIdent(id); typ != nil {
+			return typ, nil
+		}
+	}
+	return nil, ErrNotType
+}
+
+// entityName returns the declared name of a *Value, *Type or *Func,
+// or "" for any other value.
+func entityName(entity any) string {
+	switch e := entity.(type) {
+	case *Value:
+		return e.name
+	case *Type:
+		return e.name
+	case *Func:
+		return e.name
+	default:
+		return ""
+	}
+}
+
+// ReferencesOf returns the position of every identifier across p's
+// files that textually names entity. Methods are registered in
+// p.Funcs qualified as "Recv.Method" (see InspectFunc), so only the
+// unqualified method name is matched against source identifiers.
+// Matching is by name, not by the type-checked object it binds to, so
+// a shadowed identifier of the same name is reported alongside the
+// real references.
+func (p *Package) ReferencesOf(entity any) (refs []token.Position) {
+	name := entityName(entity)
+	if name == "" {
+		return nil
+	}
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, el := range p.Files.List() {
+		f := el.(*File)
+		if f.t == nil {
+			continue
+		}
+		ast.Inspect(f.t, func(n ast.Node) bool {
+			if id, ok := n.(*ast.Ident); ok && id.Name == name {
+				refs = append(refs, fset.Position(id.Pos()))
+			}
+			return true
+		})
+	}
+	return
+}
+
+// AddImport adds a named import (name may be "" for the package's
+// default name) to f's AST and returns the re-printed source. The
+// caller is responsible for re-running InspectImports if f.i must
+// reflect the change.
+func (f *File) AddImport(path, name string) []byte {
+	if f.t == nil {
+		return nil
+	}
+	astutil.AddNamedImport(fset, f.t, name, path)
+	return f.print()
+}
+
+// RemoveImport removes an import from f's AST and returns the
+// re-printed source. The caller is responsible for re-running
+// InspectImports if f.i must reflect the change.
+func (f *File) RemoveImport(path string) []byte {
+	if f.t == nil {
+		return nil
+	}
+	astutil.DeleteImport(fset, f.t, path)
+	return f.print()
+}
+
+// print re-serializes f's AST via go/printer.
+func (f *File) print() []byte {
+	var buf bytes.Buffer
+	printer.Fprint(&buf, fset, f.t)
+	return buf.Bytes()
+}